@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Alert is the structured record produced by AuditRun and WatchJob. It
+// replaces the pre-formatted strings the two code paths used to build
+// directly, so a single formatter can render bsd/rfc5424/json on demand
+// per-sink instead of baking one wire format into the audit loop.
+type Alert struct {
+	Job    string
+	Path   string
+	Change string // "mtime change", "file deleted", "file added", ...
+	Old    string
+	New    string
+	Ts     time.Time
+}
+
+func newAlert(job, path, change, oldVal, newVal string) Alert {
+	return Alert{Job: job, Path: path, Change: change, Old: oldVal, New: newVal, Ts: time.Now()}
+}
+
+// legacyLine renders an Alert as one human-readable line, "<job> <change>:
+// file=<path> old=<old> new=<new>", or "<job> <change>: <path>" when the
+// alert carries no old/new values. It's used for log output and -verify.
+func (a Alert) legacyLine() string {
+	if a.Old != "" || a.New != "" {
+		return fmt.Sprintf("%s %s: file=%s old=%s new=%s", a.Job, a.Change, a.Path, a.Old, a.New)
+	}
+	return fmt.Sprintf("%s %s: %s", a.Job, a.Change, a.Path)
+}
+
+// alertFormat renders an Alert into wire bytes for one of the supported
+// -format values: bsd (the original <105> BSD-syslog framing), rfc5424
+// (with STRUCTURED-DATA carrying job/path/change), or json.
+func alertFormat(format string, a Alert, hostname string, pid int) []byte {
+	switch format {
+	case "rfc5424":
+		sd := fmt.Sprintf(`[filewatch@32473 job="%s" path="%s" change="%s"]`, a.Job, a.Path, a.Change)
+		return []byte(fmt.Sprintf("<105>1 %s %s %s %d - %s %s",
+			a.Ts.UTC().Format(time.RFC3339), hostname, _tool, pid, sd, a.legacyLine()))
+
+	case "json":
+		rec := struct {
+			Host   string `json:"host"`
+			Pid    int    `json:"pid"`
+			Tool   string `json:"tool"`
+			Job    string `json:"job"`
+			Path   string `json:"path"`
+			Change string `json:"change"`
+			Old    string `json:"old,omitempty"`
+			New    string `json:"new,omitempty"`
+			Ts     string `json:"ts"`
+		}{hostname, pid, _tool, a.Job, a.Path, a.Change, a.Old, a.New, a.Ts.UTC().Format(time.RFC3339)}
+		b, err := json.Marshal(rec)
+		if err != nil {
+			return []byte(a.legacyLine())
+		}
+		return b
+
+	default: // "bsd"
+		hdr := fmt.Sprintf("<105>%v %v %s[%d] ", a.Ts.Format(time.RFC3339), hostname, _tool, pid)
+		return []byte(hdr + a.legacyLine())
+	}
+}
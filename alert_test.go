@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlertLegacyLine(t *testing.T) {
+	withDelta := newAlert("job[1]:", "/tmp/f", "mtime change", "old-ts", "new-ts")
+	if got := withDelta.legacyLine(); !strings.Contains(got, "old=old-ts") || !strings.Contains(got, "new=new-ts") {
+		t.Errorf("legacyLine() = %q, want old/new values present", got)
+	}
+
+	noDelta := newAlert("job[1]:", "/tmp/f", "file added", "", "")
+	if got := noDelta.legacyLine(); strings.Contains(got, "old=") {
+		t.Errorf("legacyLine() = %q, want no old=/new= for a change with no delta", got)
+	}
+}
+
+func TestAlertFormatBSD(t *testing.T) {
+	a := newAlert("job[1]:", "/tmp/f", "file deleted", "", "")
+	a.Ts = time.Unix(0, 0).UTC()
+
+	b := alertFormat("bsd", a, "host1", 42)
+	if !strings.HasPrefix(string(b), "<105>") {
+		t.Errorf("alertFormat(bsd) = %q, want <105> prefix", b)
+	}
+	if !strings.Contains(string(b), "file-notify[42]") {
+		t.Errorf("alertFormat(bsd) = %q, want tool[pid] tag", b)
+	}
+}
+
+func TestAlertFormatRFC5424(t *testing.T) {
+	a := newAlert("job[2]:", "/tmp/f", "mode change", "0644", "0777")
+	b := alertFormat("rfc5424", a, "host1", 42)
+	if !strings.Contains(string(b), `[filewatch@32473 job="job[2]:" path="/tmp/f" change="mode change"]`) {
+		t.Errorf("alertFormat(rfc5424) = %q, want STRUCTURED-DATA element", b)
+	}
+}
+
+func TestAlertFormatJSON(t *testing.T) {
+	a := newAlert("job[3]:", "/tmp/f", "hash change", "", "sha256")
+	b := alertFormat("json", a, "host1", 42)
+
+	var rec map[string]any
+	if err := json.Unmarshal(b, &rec); err != nil {
+		t.Fatalf("alertFormat(json) produced invalid JSON: %v", err)
+	}
+	if rec["change"] != "hash change" || rec["path"] != "/tmp/f" {
+		t.Errorf("alertFormat(json) = %q, want change/path fields populated", b)
+	}
+	if _, ok := rec["old"]; ok {
+		t.Errorf("alertFormat(json) = %q, want omitempty to drop an unset old field", b)
+	}
+}
+
+func TestAlertFormatUnknownDefaultsToBSD(t *testing.T) {
+	a := newAlert("job[1]:", "/tmp/f", "file added", "", "")
+	b := alertFormat("nonsense", a, "host1", 42)
+	if !strings.HasPrefix(string(b), "<105>") {
+		t.Errorf("alertFormat(unknown) = %q, want bsd fallback", b)
+	}
+}
@@ -0,0 +1,60 @@
+//go:build linux
+
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// _statTimes pulls atime/ctime out of the platform-specific Stat_t that
+// io/fs.FileInfo doesn't expose directly. Linux-only for now, matching the
+// rest of the audit internals.
+func _statTimes(info fs.FileInfo) (atime, ctime time.Time) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, time.Time{}
+	}
+	return time.Unix(st.Atim.Sec, st.Atim.Nsec), time.Unix(st.Ctim.Sec, st.Ctim.Nsec)
+}
+
+// _hashFile returns the baseline digest for path using algo ("md5", "sha1",
+// "sha256", "blake2b"). An empty or "none" algo is a no-op and returns nil,
+// which is how Hash-based comparison in AuditRun is skipped for that rule.
+func _hashFile(path, algo string) []byte {
+	var h hash.Hash
+
+	switch algo {
+	case "md5":
+		h = md5.New()
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	case "blake2b":
+		h, _ = blake2b.New256(nil)
+	default:
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return nil
+	}
+
+	return h.Sum(nil)
+}
@@ -3,14 +3,16 @@ package main
 // Importing required packages
 import (
 	"bufio"
-	"errors"
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"io/fs"
 	"log"
-	"net"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -30,65 +32,198 @@ var (
 
 type FileData struct {
 	Name  string
+	Mode  os.FileMode
+	Atime time.Time
+	Ctime time.Time
 	Mtime time.Time
+	Size  int64
+	Hash  []byte
 }
 
 type AuditPlan struct {
 	DF       string // directory/file = 'd' or 'f'
-	Presence int    // file is present, not deleted
-	Mode     int    // e.g. 7555
-	Atime    int    // file last access time
-	Ctime    int    // file inode change time
-	Mtime    int    // file last modification time
-	Hash     string // md5, etc
+	Presence int    // 1: alert on file creation/deletion
+	Mode     int    // 1: alert on permission bit changes
+	Atime    int    // 1: alert on access time changes
+	Ctime    int    // 1: alert on inode change time changes
+	Mtime    int    // 1: alert on modification time changes
+	Hash     string // md5, sha1, sha256, blake2b, or none/empty to disable
 	Path     string // fqp to file or directory
 	Prune    string // a directory to skip
+	Watch    string // poll|notify, empty defers to the -watcher default
+	Sink     string // tcp|tcp+tls|udp|unix|http|https, empty defers to -dst/-dport
+	SinkAddr string // destination for Sink, overriding -dst/-dport
+	Format   string // bsd|rfc5424|json, empty defers to the -format default
 }
 
 type AuditJob struct {
 	FileList *[]FileData
 	Audit    AuditPlan
+	mu       sync.Mutex // guards FileList across concurrent AuditRun/reload access
+	Restored bool       // FileList came from the persisted baseline, not a fresh walk
 }
 
-// Build option to track git commit/build if desired
-func Version(b bool) {
-	if b {
-		if _commit != "" {
-			// go build -ldflags="-X main._commit=$(git rev-parse --short HEAD) -X main._branch=$(git branch | awk '{print $2}')"
-			fmt.Fprintf(os.Stdout, "%s v%s (commit: %s, branch: %s)\n", _tool, _version, _commit, _branch)
-		} else {
-			// go build
-			fmt.Fprintf(os.Stdout, "%s v%s\n", _tool, _version)
-		}
-		os.Exit(0)
+// Key identifies an AuditPlan for the purpose of diffing one rules file
+// against another across a reload. Path is unique per rule line.
+func (ap AuditPlan) Key() string {
+	return ap.DF + ":" + ap.Path
+}
+
+// managedJob tracks a running RunJob or WatchJob goroutine so a SIGHUP reload can
+// cancel it independently of the others.
+type managedJob struct {
+	job    *AuditJob
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// jobManager owns the set of currently running jobs and mediates reloads
+// triggered by SIGHUP. All access to jobs goes through mu so a reload can
+// run concurrently with RunJob goroutines reading/writing FileList.
+type jobManager struct {
+	mu          sync.Mutex
+	jobs        map[string]*managedJob
+	nextNo      int
+	poll        int
+	rules       string
+	watcher     string     // default watch mode ("poll" or "notify") for rules that don't set Watch
+	defaultSink SinkConfig // fallback sink for rules that leave Sink/SinkAddr/Format blank
+}
+
+func newJobManager(rules string, poll int, watcher string, defaultSink SinkConfig) *jobManager {
+	return &jobManager{
+		jobs:        make(map[string]*managedJob),
+		poll:        poll,
+		rules:       rules,
+		watcher:     watcher,
+		defaultSink: defaultSink,
 	}
 }
 
-func TcpClient(tx string, retry, interval int) (net.Conn, error) {
+// sinkConfig resolves the effective SinkConfig for a job: its own
+// Sink/SinkAddr/Format columns take priority, falling back field-by-field to
+// the process-wide default built from -dst/-dport/-format.
+func (jm *jobManager) sinkConfig(job *AuditJob) SinkConfig {
+	cfg := jm.defaultSink
+	if job.Audit.Sink != "" {
+		cfg.Kind = job.Audit.Sink
+	}
+	if job.Audit.SinkAddr != "" {
+		cfg.Addr = job.Audit.SinkAddr
+	}
+	if job.Audit.Format != "" {
+		cfg.Format = job.Audit.Format
+	}
+	return cfg
+}
 
-	var conn net.Conn
-	var err error
+// watchMode resolves the effective mode for a job: its own Watch column
+// takes priority, falling back to the process-wide -watcher default.
+func (jm *jobManager) watchMode(job *AuditJob) string {
+	if job.Audit.Watch != "" {
+		return job.Audit.Watch
+	}
+	return jm.watcher
+}
 
-	for i := 1; i <= retry; i++ {
-		conn, err = net.Dial("tcp", tx)
-		if err == nil {
-			break
+// start launches a goroutine for job under ctx and records it so a later
+// reload can find and cancel it. Jobs in "notify" mode run as a WatchJob;
+// everything else falls back to the polling RunJob loop.
+func (jm *jobManager) start(ctx context.Context, job *AuditJob) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	jm.nextNo++
+	no := jm.nextNo
+	mode := jm.watchMode(job)
+	cfg := jm.sinkConfig(job)
+	done := make(chan struct{})
+	jm.jobs[job.Audit.Key()] = &managedJob{job: job, cancel: cancel, done: done}
+	go func() {
+		defer close(done)
+		if mode == "notify" {
+			WatchJob(jobCtx, job, no, jm.poll, cfg)
+		} else {
+			RunJob(jobCtx, job, no, jm.poll, cfg)
 		}
+	}()
+}
+
+// reload re-reads jm.rules and brings the running set of jobs in line with
+// it: unchanged rules keep their goroutine (and FileList/hash baseline),
+// changed or removed rules are cancelled, and new rules are started.
+func (jm *jobManager) reload(ctx context.Context) {
+	log.Println("SIGHUP received, reloading audit rules")
+
+	auditRules, err := _loadAuditRulesForReload(jm.rules)
+	if err != nil {
+		log.Printf("reload: failed to load rules, keeping existing jobs: %v\n", err)
+		return
+	}
 
-		if errors.Is(err, syscall.ECONNREFUSED) {
-			log.Printf("TCP connection attempt %d: ECONNREFUSED: %v\n", i, err)
-			if i == retry {
-				err1 := errors.New("TCP connection attempts exhausted")
-				return nil, err1
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, ap := range *auditRules {
+		key := ap.Key()
+		seen[key] = true
+
+		if existing, ok := jm.jobs[key]; ok {
+			if reflect.DeepEqual(existing.job.Audit, ap) {
+				continue // unchanged: keep the goroutine and its FileList
 			}
-		} else {
-			return nil, err
+			log.Printf("reload: rule changed, restarting: %s\n", key)
+			existing.cancel()
+			<-existing.done
+			delete(jm.jobs, key)
+		}
+
+		j := _buildJob(ap, "reload: "+key)
+		if j == nil {
+			log.Printf("reload: skipping rule, inventory build failed: %s\n", key)
+			continue
+		}
+		jm.start(ctx, j)
+	}
+
+	for key, existing := range jm.jobs {
+		if !seen[key] {
+			log.Printf("reload: rule removed, stopping: %s\n", key)
+			existing.cancel()
+			<-existing.done
+			delete(jm.jobs, key)
 		}
+	}
+
+	log.Printf("reload: %d jobs running\n", len(jm.jobs))
+}
 
-		time.Sleep(time.Duration(interval) * time.Second)
+// wait blocks until every managed job's goroutine has returned, used during
+// shutdown to drain in-flight AuditRun calls before the log file is closed.
+func (jm *jobManager) wait() {
+	jm.mu.Lock()
+	dones := make([]chan struct{}, 0, len(jm.jobs))
+	for _, j := range jm.jobs {
+		dones = append(dones, j.done)
 	}
+	jm.mu.Unlock()
 
-	return conn, err
+	for _, d := range dones {
+		<-d
+	}
+}
+
+// Build option to track git commit/build if desired
+func Version(b bool) {
+	if b {
+		if _commit != "" {
+			// go build -ldflags="-X main._commit=$(git rev-parse --short HEAD) -X main._branch=$(git branch | awk '{print $2}')"
+			fmt.Fprintf(os.Stdout, "%s v%s (commit: %s, branch: %s)\n", _tool, _version, _commit, _branch)
+		} else {
+			// go build
+			fmt.Fprintf(os.Stdout, "%s v%s\n", _tool, _version)
+		}
+		os.Exit(0)
+	}
 }
 
 func _startLog(fileName string) *os.File {
@@ -106,15 +241,37 @@ func _startLog(fileName string) *os.File {
 	return fLog
 }
 
+// _loadAuditRules opens and parses confFile, exiting the process if it
+// can't be opened. Used only at startup, where there's no prior job set to
+// fall back to; reload uses _loadAuditRulesForReload instead so a
+// transiently unreadable rules file doesn't take the daemon down.
 func _loadAuditRules(confFile string) *[]AuditPlan {
 	f, err := os.Open(confFile)
 	if err != nil {
-		s := fmt.Sprintf("fatal error: %v", err)
-		fmt.Fprintf(os.Stderr, "%s\n", s)
-		log.Fatal(s)
+		Log(FacRules).Fatalf("fatal error: %v", err)
+	}
+	defer f.Close()
+
+	return _parseAuditRules(f)
+}
+
+// _loadAuditRulesForReload is _loadAuditRules's non-fatal counterpart: it
+// returns an open error instead of exiting, so jobManager.reload can log
+// and keep the existing jobs running.
+func _loadAuditRulesForReload(confFile string) (*[]AuditPlan, error) {
+	f, err := os.Open(confFile)
+	if err != nil {
+		return nil, err
 	}
 	defer f.Close()
 
+	return _parseAuditRules(f), nil
+}
+
+// _parseAuditRules scans an already-open rules file into AuditPlans, one
+// per non-comment "d|f"-prefixed line. Shared by _loadAuditRules and
+// _loadAuditRulesForReload.
+func _parseAuditRules(f *os.File) *[]AuditPlan {
 	apList := make([]AuditPlan, 0)
 
 	scanner := bufio.NewScanner(f)
@@ -172,34 +329,63 @@ func _loadAuditRules(confFile string) *[]AuditPlan {
 		ap.Path = field[7]
 		ap.Prune = field[8]
 
+		if len(field) > 9 {
+			ap.Watch = field[9]
+		}
+		if len(field) > 10 {
+			ap.Sink = field[10]
+		}
+		if len(field) > 11 {
+			ap.SinkAddr = field[11]
+		}
+		if len(field) > 12 {
+			ap.Format = field[12]
+		}
+
 		apList = append(apList, ap)
 		planCount++
 	}
 
-	log.Printf("audit plans loaded: %d\n", planCount)
+	Log(FacRules).Infof("audit plans loaded: %d", planCount)
+	metricRulesLoaded.Set(float64(planCount))
 	return &apList
 }
 
+// _newFileData captures the baseline fields AuditRun will later compare
+// against, hashing the file only when the rule asks for one.
+func _newFileData(path string, info fs.FileInfo, ap AuditPlan) FileData {
+	filedata := FileData{Name: path, Mode: info.Mode(), Mtime: info.ModTime(), Size: info.Size()}
+	filedata.Atime, filedata.Ctime = _statTimes(info)
+	if ap.Hash != "" && ap.Hash != "none" {
+		filedata.Hash = _hashFile(path, ap.Hash)
+		// _hashFile's read just bumped the file's own on-disk atime; re-stat
+		// so the stored baseline reflects that instead of the pre-hash
+		// atime, otherwise the first comparison pass trips an atime alert
+		// this tool's own hash check caused (see _auditCompare's matching fixup).
+		if fresh, err := os.Stat(path); err == nil {
+			filedata.Atime, _ = _statTimes(fresh)
+		}
+	}
+	return filedata
+}
+
 func _buildFileInvenory(ap AuditPlan) *[]FileData {
 	fd := make([]FileData, 0)
 	subDirToSkip := ap.Prune
 	root := ap.Path
 
 	err := filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
-		filedata := FileData{}
-
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error walking directory %q: %v\n", path, err)
 			return err
 		}
 
 		if !info.IsDir() {
-			filedata.Name = path
-			filedata.Mtime = info.ModTime()
+			filedata := _newFileData(path, info, ap)
 			fd = append(fd, filedata)
-			log.Printf("add: mtime=%v file=%s", filedata.Mtime, filedata.Name)
+			Log(FacWalk).Debugf("add: mtime=%v file=%s", filedata.Mtime, filedata.Name)
 		} else if info.IsDir() && info.Name() == subDirToSkip {
-			log.Printf("skip: %v\n", path)
+			Log(FacWalk).Debugf("skip: %v", path)
 			return filepath.SkipDir
 		}
 
@@ -207,23 +393,49 @@ func _buildFileInvenory(ap AuditPlan) *[]FileData {
 	})
 
 	if err != nil {
-		log.Printf("error walking directory %q: %v\n", root, err)
+		Log(FacWalk).Errorf("error walking directory %q: %v", root, err)
 		return nil
 	}
 
 	return &fd
 }
 
-func _buildJobs(rules *[]AuditPlan) *[]AuditJob {
+// _buildJob constructs a single AuditJob for ap, preferring a restored
+// baseline (see the package-level baseline store) over a fresh
+// _buildFileInvenory walk. It's shared by _buildJobs (startup) and
+// jobManager.reload (SIGHUP) so a rule added after startup gets the same
+// "diff against last known state" treatment as one present at boot.
+// Returns nil if the job's FileList couldn't be built. label identifies the
+// rule in log output (the "line N" counter _buildJobs keeps at startup, the
+// rule key on a SIGHUP reload).
+func _buildJob(ap AuditPlan, label string) *AuditJob {
+	j := &AuditJob{FileList: nil, Audit: ap}
+
+	if fd, ok := baseline.get(ap.Key()); ok {
+		restored := append([]FileData(nil), fd...)
+		j.FileList = &restored
+		j.Restored = true
+		Log(FacRules).Infof("%s: restored %d files from persisted state for %s", label, len(restored), ap.Path)
+	} else {
+		j.FileList = _buildFileInvenory(ap)
+	}
+
+	if j.FileList == nil {
+		return nil
+	}
+	return j
+}
+
+func _buildJobs(rules *[]AuditPlan) *[]*AuditJob {
 
-	jobs := make([]AuditJob, 0)
+	jobs := make([]*AuditJob, 0)
 
 	line := 1
 	for _, v := range *rules {
-		a := AuditPlan{DF: v.DF, Presence: v.Presence, Mode: v.Mode, Atime: v.Atime, Ctime: v.Ctime, Mtime: v.Mtime, Hash: v.Hash, Path: v.Path, Prune: v.Prune}
-		j := AuditJob{FileList: nil, Audit: a}
-		j.FileList = _buildFileInvenory(a)
-		if j.FileList == nil {
+		a := AuditPlan{DF: v.DF, Presence: v.Presence, Mode: v.Mode, Atime: v.Atime, Ctime: v.Ctime, Mtime: v.Mtime, Hash: v.Hash, Path: v.Path, Prune: v.Prune, Watch: v.Watch, Sink: v.Sink, SinkAddr: v.SinkAddr, Format: v.Format}
+
+		j := _buildJob(a, fmt.Sprintf("line %d", line))
+		if j == nil {
 			s := fmt.Sprintf("skipping line %d of %s.rules. check rules and file system path", line, _tool)
 			log.Println(s)
 			fmt.Fprintln(os.Stderr, s)
@@ -236,83 +448,252 @@ func _buildJobs(rules *[]AuditPlan) *[]AuditJob {
 	return &jobs
 }
 
-func Initialize(rules string) *[]AuditJob {
+// Initialize builds the job set for rules. Each job's FileList prefers the
+// persisted baseline (see the package-level baseline store) over a fresh
+// walk, falling back to a fresh walk for any rule with no prior state.
+func Initialize(rules string) *[]*AuditJob {
 	auditRules := _loadAuditRules(rules)
 	auditJobs := _buildJobs(auditRules)
 	auditRules = nil
 	return auditJobs
 }
 
-func AuditRun(ap AuditJob, jobNo int, tx string) int {
+// _auditCompare is AuditRun's comparison pass, pulled out so -verify can run
+// it once, print what it finds, and exit without touching a sink or the
+// state file. It stats every file already in ap.FileList, walks for new
+// ones when Presence asks for it, and leaves *ap.FileList holding the
+// refreshed baseline.
+func _auditCompare(ap *AuditJob, jobNo int) []Alert {
 
 	job := fmt.Sprintf("job[%d]:", jobNo)
-	alertBoard := make([]string, 0)
+	alertBoard := make([]Alert, 0)
+
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+
+	seen := make(map[string]bool, len(*ap.FileList))
+	updated := make([]FileData, 0, len(*ap.FileList))
+
 	for _, v := range *ap.FileList {
-		if debug {
-			log.Printf("%s audit %s", job, v.Name)
-		}
+		seen[v.Name] = true
+		Log(FacWalk).Debugf("%s audit %s", job, v.Name)
 
 		info, err := os.Stat(v.Name)
 		if err != nil {
-			s := fmt.Sprintf("%s file deletion: %v", job, err)
-			log.Println(s)
-			alertBoard = append(alertBoard, s)
-			continue
+			if ap.Audit.Presence != 1 {
+				// Presence tracking is off for this rule: keep the entry as
+				// is so a transient stat failure doesn't silently drop it
+				// from future comparisons.
+				updated = append(updated, v)
+				continue
+			}
+			a := newAlert(job, v.Name, "file deleted", "", "")
+			Log(FacWalk).Infof("%s", a.legacyLine())
+			alertBoard = append(alertBoard, a)
+			continue // drop from the baseline, it's gone
 		}
 
-		if ap.Audit.Mtime == 1 {
-			if info.ModTime() == v.Mtime {
-				continue
-			} else {
-				s := fmt.Sprintf("%s mtime change: file=%s mtime1=%v mtime0=%v", job, v.Name, info.ModTime(), v.Mtime)
-				log.Println(s)
-				alertBoard = append(alertBoard, s)
+		changed := false
+
+		if ap.Audit.Mtime == 1 && !info.ModTime().Equal(v.Mtime) {
+			a := newAlert(job, v.Name, "mtime change", v.Mtime.String(), info.ModTime().String())
+			Log(FacWalk).Infof("%s", a.legacyLine())
+			alertBoard = append(alertBoard, a)
+			changed = true
+		}
+
+		if ap.Audit.Mode == 1 && info.Mode() != v.Mode {
+			a := newAlert(job, v.Name, "mode change", v.Mode.String(), info.Mode().String())
+			Log(FacWalk).Infof("%s", a.legacyLine())
+			alertBoard = append(alertBoard, a)
+			changed = true
+		}
+
+		if ap.Audit.Atime == 1 || ap.Audit.Ctime == 1 {
+			atime, ctime := _statTimes(info)
+			if ap.Audit.Atime == 1 && !atime.Equal(v.Atime) {
+				a := newAlert(job, v.Name, "atime change", v.Atime.String(), atime.String())
+				Log(FacWalk).Infof("%s", a.legacyLine())
+				alertBoard = append(alertBoard, a)
+				changed = true
+			}
+			if ap.Audit.Ctime == 1 && !ctime.Equal(v.Ctime) {
+				a := newAlert(job, v.Name, "ctime change", v.Ctime.String(), ctime.String())
+				Log(FacWalk).Infof("%s", a.legacyLine())
+				alertBoard = append(alertBoard, a)
+				changed = true
+			}
+		}
+
+		if ap.Audit.Hash != "" && ap.Audit.Hash != "none" {
+			h := _hashFile(v.Name, ap.Audit.Hash)
+			if !bytes.Equal(h, v.Hash) {
+				a := newAlert(job, v.Name, "hash change", "", ap.Audit.Hash)
+				Log(FacWalk).Infof("%s", a.legacyLine())
+				alertBoard = append(alertBoard, a)
+				changed = true
+			}
+			v.Hash = h
+		}
+
+		if changed {
+			v.Mode = info.Mode()
+			v.Mtime = info.ModTime()
+			v.Size = info.Size()
+			v.Atime, v.Ctime = _statTimes(info)
+		}
+
+		if ap.Audit.Hash != "" && ap.Audit.Hash != "none" {
+			// _hashFile above just bumped the file's own on-disk atime via
+			// its read; re-stat so the stored baseline reflects that bump
+			// instead of the pre-hash atime from the top of this loop,
+			// otherwise the next poll's atime compare trips on a change
+			// this poll's own hash check caused.
+			if fresh, err := os.Stat(v.Name); err == nil {
+				v.Atime, _ = _statTimes(fresh)
 			}
 		}
+
+		updated = append(updated, v)
+	}
+
+	if ap.Audit.Presence == 1 {
+		err := filepath.Walk(ap.Audit.Path, func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if info.Name() == ap.Audit.Prune {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if seen[path] {
+				return nil
+			}
+			a := newAlert(job, path, "file added", "", "")
+			Log(FacWalk).Infof("%s", a.legacyLine())
+			alertBoard = append(alertBoard, a)
+			updated = append(updated, _newFileData(path, info, ap.Audit))
+			seen[path] = true
+			return nil
+		})
+		if err != nil {
+			Log(FacWalk).Errorf("%s error walking directory %q: %v", job, ap.Audit.Path, err)
+		}
 	}
 
+	*ap.FileList = updated
+
+	return alertBoard
+}
+
+// AuditRun runs one comparison pass for ap, persists the refreshed baseline
+// (a no-op when -state wasn't given), records metrics, and dispatches any
+// alerts through cfg.
+func AuditRun(ap *AuditJob, jobNo int, cfg SinkConfig) int {
+	jobLabel := strconv.Itoa(jobNo)
+	start := time.Now()
+
+	alertBoard := _auditCompare(ap, jobNo)
+
+	ap.mu.Lock()
+	snapshot := append([]FileData(nil), *ap.FileList...)
+	ap.mu.Unlock()
+	baseline.save(ap.Audit.Key(), snapshot)
+
+	recordRunMetrics(jobLabel, start, len(snapshot), alertBoard)
+
+	return dispatchAlerts(alertBoard, cfg)
+}
+
+// recordRunMetrics records the per-job metrics shared by AuditRun's poll
+// passes and WatchJob's event-driven callbacks, so a rule running in
+// notify mode contributes to filewatch_audit_runs_total,
+// filewatch_audit_duration_seconds, filewatch_files_watched, and
+// filewatch_alerts_total the same way a poll-mode rule does.
+func recordRunMetrics(jobLabel string, start time.Time, fileCount int, alertBoard []Alert) {
+	metricAuditRuns.WithLabelValues(jobLabel).Inc()
+	metricAuditDuration.WithLabelValues(jobLabel).Observe(time.Since(start).Seconds())
+	metricFilesWatched.WithLabelValues(jobLabel).Set(float64(fileCount))
+	for _, a := range alertBoard {
+		metricAlerts.WithLabelValues(jobLabel, a.Change).Inc()
+	}
+}
+
+// runVerify is the -verify subcommand: it builds jobs the same way
+// Initialize does (preferring persisted state over a fresh walk), compares
+// each against the live filesystem exactly once, prints whatever drifted,
+// and exits. It never opens a sink and never writes back to the state
+// file, so it's safe to run against a live daemon's state.db for a
+// forensic or CI check.
+func runVerify(rules string) {
+	jobs := Initialize(rules)
+
+	drift := 0
+	for i, job := range *jobs {
+		for _, a := range _auditCompare(job, i+1) {
+			fmt.Println(a.legacyLine())
+			drift++
+		}
+	}
+
+	if drift > 0 {
+		fmt.Fprintf(os.Stderr, "verify: %d divergences from baseline\n", drift)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// dispatchAlerts is the single place Alerts turn into wire traffic, shared
+// by the polling (AuditRun) and event-driven (WatchJob) code paths so both
+// emit identically formatted output through whichever sink cfg names.
+func dispatchAlerts(alertBoard []Alert, cfg SinkConfig) int {
 	n := len(alertBoard)
-	log.Println(n, "alerts")
+	Log(FacTx).Infof("%d alerts", n)
 
 	if n > 0 {
-		now := time.Now().Format(time.RFC3339)
 		hostname, _ := os.Hostname()
 		pid := os.Getpid()
-		// <105> = audit.alert (facility.severity)
-		hdr := fmt.Sprintf("<105>%v %v %s[%d] ", now, hostname, _tool, pid)
-		client, err := TcpClient(tx, 2, 2)
-		if err != nil {
-			log.Println(err)
-		}
-
-		if client != nil {
-			for _, v := range alertBoard {
-				_, err = client.Write([]byte(hdr + v))
-				if err != nil {
-					log.Println(err)
-				}
+		sink := sinks.get(cfg)
+		for _, a := range alertBoard {
+			if err := sink.Send(alertFormat(cfg.Format, a, hostname, pid)); err != nil {
+				Log(FacTx).Errorf("%v", err)
 			}
-			client.Close()
 		}
 	}
 
 	return n
 }
 
-func RunJob(job AuditJob, jobNo, poll int, wg *sync.WaitGroup, tx string) {
+func RunJob(ctx context.Context, job *AuditJob, jobNo, poll int, cfg SinkConfig) {
 
 	s := fmt.Sprintf("job[%d]:", jobNo)
-	if debug {
-		log.Printf("%s watching directory %s\n", s, job.Audit.Path)
-		log.Printf("%s jitter %v", s, time.Duration(jobNo)*time.Minute)
+	Log(FacWalk).Debugf("%s watching directory %s", s, job.Audit.Path)
+	Log(FacWalk).Debugf("%s jitter %v", s, time.Duration(jobNo*30)*time.Second)
+
+	select {
+	case <-time.After(time.Duration(jobNo*30) * time.Second):
+	case <-ctx.Done():
+		return
 	}
+	Log(FacWalk).Infof("%s entering audit loop", s)
 
-	time.Sleep(time.Duration(jobNo*30) * time.Second)
-	log.Println(s, "entering audit loop")
+	if job.Restored {
+		Log(FacWalk).Infof("%s checking restored baseline for drift since last run", s)
+		AuditRun(job, jobNo, cfg)
+	}
 
 	ticker := time.NewTicker(time.Duration(poll) * time.Minute)
-	for range ticker.C {
-		AuditRun(job, jobNo, tx)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			AuditRun(job, jobNo, cfg)
+		case <-ctx.Done():
+			Log(FacWalk).Infof("%s shutting down", s)
+			return
+		}
 	}
 }
 
@@ -320,29 +701,81 @@ func main() {
 
 	_dst := flag.String("dst", "127.0.0.1", "Destination hostname or IP address")
 	_dport := flag.Int("dport", 6000, "Destination port")
+	_sink := flag.String("sink", "tcp", "default alert sink: tcp|tcp+tls|udp|unix|http|https")
+	_format := flag.String("format", "bsd", "default alert format: bsd|rfc5424|json")
+	_tlsCert := flag.String("tls-cert", "", "client certificate for tcp+tls sinks (mTLS)")
+	_tlsKey := flag.String("tls-key", "", "client key for tcp+tls sinks (mTLS)")
+	_tlsCA := flag.String("tls-ca", "", "CA certificate for verifying tcp+tls sinks, e.g. an internal collector's CA")
 	_debug := flag.Bool("debug", false, "Enable debug")
+	_logLevel := flag.String("log-level", "info", "log level: debug|info|warn|error")
+	_logFormat := flag.String("log-format", "text", "log line formatter: text|json")
 	_log := flag.String("log", "file-watch.log", "log file")
 	_poll := flag.Int("poll", 1, "poll time")
 	_rules := flag.String("rules", "file-watch.rules", "rules configuration file")
+	_watcher := flag.String("watcher", "poll", "default watch mode for rules without a mode column: poll|notify")
+	_listen := flag.String("listen", "", "address for the /metrics, /healthz, /readyz, /rules HTTP server (disabled if empty)")
+	_state := flag.String("state", "", "path to a persisted baseline file, e.g. /var/lib/file-watch/state.db (disabled if empty)")
+	_verify := flag.Bool("verify", false, "load the baseline, walk once, print any drift, and exit without opening a sink")
 	_version := flag.Bool("version", false, "Display version and exit")
 	flag.Parse()
 
 	Version(*_version)
 	debug = *_debug
-	tx := *_dst + ":" + strconv.Itoa(*_dport)
+	if debug {
+		*_logLevel = "debug" // -debug is kept as a shorthand for -log-level=debug
+	}
+	initLogging(*_logLevel, *_logFormat)
+
+	if *_state != "" {
+		baseline = openStateStore(*_state)
+	}
+
+	if *_verify {
+		runVerify(*_rules)
+	}
+
+	defaultSink := SinkConfig{
+		Kind:    *_sink,
+		Addr:    *_dst + ":" + strconv.Itoa(*_dport),
+		Format:  *_format,
+		TLSCert: *_tlsCert,
+		TLSKey:  *_tlsKey,
+		TLSCA:   *_tlsCA,
+	}
+	if *_sink == "unix" || *_sink == "http" || *_sink == "https" {
+		defaultSink.Addr = *_dst // -dst doubles as the unix path or webhook URL for those sinks
+	}
 
 	fhLog := _startLog(*_log)
 	defer fhLog.Close()
 	log.Printf("poll: %dm\n", *_poll)
 
-	aj := Initialize(*_rules)
+	ctx, cancel := context.WithCancel(context.Background())
 
-	var wg sync.WaitGroup
+	jm := newJobManager(*_rules, *_poll, *_watcher, defaultSink)
+	for _, job := range *Initialize(*_rules) {
+		jm.start(ctx, job)
+	}
 
-	for n, job := range *aj {
-		go RunJob(job, n, *_poll, &wg, tx)
-		wg.Add(1)
+	if *_listen != "" {
+		startMetricsServer(ctx, *_listen, jm)
 	}
 
-	wg.Wait()
+	// Signal handling lives here in main only; RunJob goroutines never
+	// install their own handlers, so there's nothing for them to ignore.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+
+	for sig := range sigCh {
+		switch sig {
+		case syscall.SIGHUP:
+			jm.reload(ctx)
+		case syscall.SIGINT, syscall.SIGTERM:
+			log.Printf("%v received, shutting down\n", sig)
+			cancel()
+			jm.wait()
+			signal.Stop(sigCh)
+			return
+		}
+	}
 }
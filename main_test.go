@@ -0,0 +1,195 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestAuditJob(ap AuditPlan, fd []FileData) *AuditJob {
+	list := append([]FileData(nil), fd...)
+	return &AuditJob{FileList: &list, Audit: ap}
+}
+
+func hasChange(alerts []Alert, change, path string) bool {
+	for _, a := range alerts {
+		if a.Change == change && a.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// TestNewFileDataHashRefreshesAtime guards against the Atime+Hash
+// interaction bug: _hashFile's read bumps the file's own on-disk atime, so
+// the baseline _newFileData stores must reflect the post-hash stat, not
+// the one taken before hashing, or the very first comparison pass trips a
+// self-inflicted "atime change" alert.
+func TestNewFileDataHashRefreshesAtime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fd := _newFileData(path, info, AuditPlan{Atime: 1, Hash: "sha256"})
+
+	fresh, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAtime, _ := _statTimes(fresh)
+	if !fd.Atime.Equal(wantAtime) {
+		t.Errorf("_newFileData Atime = %v, want post-hash stat's atime %v", fd.Atime, wantAtime)
+	}
+}
+
+// TestAuditCompareAtimeHashNoFalsePositive guards the same interaction in
+// _auditCompare's poll-to-poll comparison: once a rule's stored Atime
+// baseline reflects a prior poll's own hash read, the next poll must not
+// re-alert on the atime its own hash check is about to cause again.
+func TestAuditCompareAtimeHashNoFalsePositive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ap := AuditPlan{Atime: 1, Hash: "sha256"}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := _newFileData(path, info, ap)
+
+	job := newTestAuditJob(ap, []FileData{baseline})
+	alerts := _auditCompare(job, 1)
+
+	if hasChange(alerts, "atime change", path) {
+		t.Errorf("_auditCompare fired a self-inflicted atime change alert: %+v", alerts)
+	}
+
+	fresh, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAtime, _ := _statTimes(fresh)
+	if got := (*job.FileList)[0].Atime; !got.Equal(wantAtime) {
+		t.Errorf("stored Atime baseline = %v, want refreshed post-hash atime %v", got, wantAtime)
+	}
+}
+
+func TestAuditCompareDetectsMtimeAndModeChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ap := AuditPlan{Mtime: 1, Mode: 1}
+	baseline := FileData{Name: path, Mode: 0600, Mtime: info.ModTime().Add(-time.Hour)}
+
+	job := newTestAuditJob(ap, []FileData{baseline})
+	alerts := _auditCompare(job, 1)
+
+	if !hasChange(alerts, "mtime change", path) {
+		t.Errorf("_auditCompare missed mtime change: %+v", alerts)
+	}
+	if !hasChange(alerts, "mode change", path) {
+		t.Errorf("_auditCompare missed mode change: %+v", alerts)
+	}
+}
+
+func TestAuditComparePresenceAddedAndDeleted(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "kept")
+	added := filepath.Join(dir, "added")
+	if err := os.WriteFile(kept, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ap := AuditPlan{Presence: 1, DF: "d", Path: dir}
+	info, err := os.Stat(kept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := []FileData{
+		_newFileData(kept, info, ap),
+		{Name: filepath.Join(dir, "gone")},
+	}
+
+	if err := os.WriteFile(added, []byte("y"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job := newTestAuditJob(ap, baseline)
+	alerts := _auditCompare(job, 1)
+
+	if !hasChange(alerts, "file added", added) {
+		t.Errorf("_auditCompare missed file added: %+v", alerts)
+	}
+	if !hasChange(alerts, "file deleted", filepath.Join(dir, "gone")) {
+		t.Errorf("_auditCompare missed file deleted: %+v", alerts)
+	}
+
+	names := make(map[string]bool)
+	for _, fd := range *job.FileList {
+		names[fd.Name] = true
+	}
+	if names[filepath.Join(dir, "gone")] {
+		t.Error("deleted file wasn't dropped from the refreshed baseline")
+	}
+	if !names[added] {
+		t.Error("added file wasn't added to the refreshed baseline")
+	}
+}
+
+func TestBuildJobRestoresFromBaseline(t *testing.T) {
+	orig := baseline
+	defer func() { baseline = orig }()
+
+	ap := AuditPlan{DF: "f", Path: "/tmp/restored"}
+	store := openStateStore("")
+	store.data[ap.Key()] = []FileData{{Name: "/tmp/restored", Size: 7}}
+	baseline = store
+
+	j := _buildJob(ap, "test")
+	if j == nil {
+		t.Fatal("_buildJob returned nil")
+	}
+	if !j.Restored {
+		t.Error("_buildJob didn't set Restored for a rule with a persisted baseline")
+	}
+	if len(*j.FileList) != 1 || (*j.FileList)[0].Name != "/tmp/restored" {
+		t.Errorf("_buildJob FileList = %+v, want the restored baseline", *j.FileList)
+	}
+}
+
+func TestBuildJobFallsBackToFreshWalk(t *testing.T) {
+	orig := baseline
+	defer func() { baseline = orig }()
+	baseline = openStateStore("")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "f"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	j := _buildJob(AuditPlan{DF: "d", Path: dir}, "test")
+	if j == nil {
+		t.Fatal("_buildJob returned nil")
+	}
+	if j.Restored {
+		t.Error("_buildJob set Restored with no persisted baseline")
+	}
+	if len(*j.FileList) != 1 {
+		t.Errorf("_buildJob FileList = %+v, want one walked entry", *j.FileList)
+	}
+}
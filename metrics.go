@@ -0,0 +1,43 @@
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics exposed on /metrics when -listen is set. Labels are kept small
+// (job number, alert type, and the sink's "kind|addr|format" key) since each
+// rule line is already its own time series via the "job" label.
+var (
+	metricAuditRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "filewatch_audit_runs_total",
+		Help: "Number of completed audit passes (AuditRun polls and WatchJob event callbacks), per job.",
+	}, []string{"job"})
+
+	metricAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "filewatch_alerts_total",
+		Help: "Number of alerts raised, per job and change type.",
+	}, []string{"job", "type"})
+
+	metricFilesWatched = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "filewatch_files_watched",
+		Help: "Number of files currently tracked in a job's baseline.",
+	}, []string{"job"})
+
+	metricAuditDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "filewatch_audit_duration_seconds",
+		Help:    "Wall time of a single audit pass (AuditRun poll or WatchJob event callback), per job.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"job"})
+
+	metricSinkFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "filewatch_sink_failures_total",
+		Help: "Dial/write failures per alert sink.",
+	}, []string{"sink"})
+
+	metricRulesLoaded = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "filewatch_rules_loaded",
+		Help: "Number of audit plans currently loaded from the rules file.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(metricAuditRuns, metricAlerts, metricFilesWatched, metricAuditDuration, metricSinkFailures, metricRulesLoaded)
+}
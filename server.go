@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsShutdownTimeout = 5 * time.Second
+
+// rulesSnapshot returns the AuditPlan of every currently running job, used
+// by the /rules debug endpoint. It does not include rules that failed to
+// build an inventory and were dropped at startup or reload.
+func (jm *jobManager) rulesSnapshot() []AuditPlan {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	plans := make([]AuditPlan, 0, len(jm.jobs))
+	for _, j := range jm.jobs {
+		plans = append(plans, j.job.Audit)
+	}
+	return plans
+}
+
+// startMetricsServer exposes /metrics (Prometheus text format), /healthz,
+// /readyz, and a /rules debug dump on addr. It's tied to ctx so the
+// graceful-shutdown path introduced for SIGINT/SIGTERM also stops it.
+func startMetricsServer(ctx context.Context, addr string, jm *jobManager) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if len(jm.rulesSnapshot()) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("no jobs running"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ready"))
+	})
+
+	mux.HandleFunc("/rules", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(jm.rulesSnapshot()); err != nil {
+			Log(FacMain).Errorf("/rules encode failed: %v", err)
+		}
+	})
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		Log(FacMain).Infof("metrics server listening on %s", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			Log(FacMain).Errorf("metrics server: %v", err)
+		}
+	}()
+}
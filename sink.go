@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SinkConfig describes where one rule's alerts go and how they're framed.
+// It's built from the AuditPlan Sink/SinkAddr/Format columns, falling back
+// to the process-wide -dst/-dport/-format flags when a rule leaves them
+// blank, so a single file-watch.rules can still fan out to several
+// collectors.
+type SinkConfig struct {
+	Kind   string // tcp, tcp+tls, udp, unix, http, https
+	Addr   string // host:port for tcp/tcp+tls/udp, path for unix, URL for http/https
+	Format string // bsd, rfc5424, json
+	// TLSCert/TLSKey/TLSCA are optional PEM file paths for mTLS on tcp+tls.
+	TLSCert string
+	TLSKey  string
+	TLSCA   string
+}
+
+func (sc SinkConfig) key() string {
+	return sc.Kind + "|" + sc.Addr + "|" + sc.Format
+}
+
+// AlertSink is the transport abstraction AuditRun/WatchJob alert through.
+// Concrete sinks never block the audit loop: sends are buffered and a
+// background goroutine owns reconnection and backoff.
+type AlertSink interface {
+	Send(b []byte) error
+	Close() error
+}
+
+// bufferedSink is the one AlertSink implementation. It owns a single
+// outbound connection (or, for http, issues one request per message), reads
+// off a buffered channel, and applies exponential backoff with jitter when
+// the destination is refusing connections. When the buffer is full, sends
+// are dropped and counted rather than blocking the caller.
+type bufferedSink struct {
+	cfg     SinkConfig
+	ch      chan []byte
+	done    chan struct{}
+	dropped uint64
+}
+
+const (
+	_sinkBufferSize = 1024
+	_sinkMinBackoff = 500 * time.Millisecond
+	_sinkMaxBackoff = 30 * time.Second
+)
+
+func newBufferedSink(cfg SinkConfig) *bufferedSink {
+	s := &bufferedSink{cfg: cfg, ch: make(chan []byte, _sinkBufferSize), done: make(chan struct{})}
+	go s.run()
+	return s
+}
+
+func (s *bufferedSink) Send(b []byte) error {
+	select {
+	case s.ch <- b:
+		return nil
+	default:
+		n := atomic.AddUint64(&s.dropped, 1)
+		if n == 1 || n%100 == 0 {
+			Log(FacTx).Warnf("sink %s: buffer full, dropped %d alerts so far", s.cfg.key(), n)
+		}
+		return fmt.Errorf("sink %s: buffer full", s.cfg.key())
+	}
+}
+
+func (s *bufferedSink) Close() error {
+	close(s.done)
+	return nil
+}
+
+func (s *bufferedSink) run() {
+	var conn net.Conn
+	backoff := _sinkMinBackoff
+
+	closeConn := func() {
+		if conn != nil {
+			conn.Close()
+			conn = nil
+		}
+	}
+	defer closeConn()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg := <-s.ch:
+			if s.cfg.Kind == "http" || s.cfg.Kind == "https" {
+				if err := s.sendHTTP(msg); err != nil {
+					Log(FacTx).Errorf("sink %s: %v", s.cfg.key(), err)
+					metricSinkFailures.WithLabelValues(s.cfg.key()).Inc()
+				}
+				continue
+			}
+
+			if conn == nil {
+				c, err := s.dial()
+				if err != nil {
+					Log(FacTx).Warnf("sink %s: dial failed, retrying in %v: %v", s.cfg.key(), backoff, err)
+					metricSinkFailures.WithLabelValues(s.cfg.key()).Inc()
+					time.Sleep(jitter(backoff))
+					backoff = nextBackoff(backoff)
+					// the message that triggered this attempt is dropped;
+					// the next queued message triggers the next dial.
+					continue
+				}
+				conn = c
+				backoff = _sinkMinBackoff
+			}
+
+			if _, err := conn.Write(msg); err != nil {
+				Log(FacTx).Errorf("sink %s: write failed, reconnecting: %v", s.cfg.key(), err)
+				metricSinkFailures.WithLabelValues(s.cfg.key()).Inc()
+				closeConn()
+			}
+		}
+	}
+}
+
+func (s *bufferedSink) dial() (net.Conn, error) {
+	switch s.cfg.Kind {
+	case "tcp":
+		return net.Dial("tcp", s.cfg.Addr)
+	case "udp":
+		return net.Dial("udp", s.cfg.Addr)
+	case "unix":
+		return net.Dial("unix", s.cfg.Addr)
+	case "tcp+tls":
+		conf := &tls.Config{}
+		if s.cfg.TLSCert != "" && s.cfg.TLSKey != "" {
+			cert, err := tls.LoadX509KeyPair(s.cfg.TLSCert, s.cfg.TLSKey)
+			if err != nil {
+				return nil, err
+			}
+			conf.Certificates = []tls.Certificate{cert}
+		}
+		if s.cfg.TLSCA != "" {
+			pem, err := os.ReadFile(s.cfg.TLSCA)
+			if err != nil {
+				return nil, err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("tls-ca %s: no certificates found", s.cfg.TLSCA)
+			}
+			conf.RootCAs = pool
+		}
+		return tls.Dial("tcp", s.cfg.Addr, conf)
+	default:
+		return nil, fmt.Errorf("unsupported sink kind %q", s.cfg.Kind)
+	}
+}
+
+func (s *bufferedSink) sendHTTP(msg []byte) error {
+	url := s.cfg.Addr
+	resp, err := http.Post(url, "application/json", bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: unexpected status %s", url, resp.Status)
+	}
+	return nil
+}
+
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > _sinkMaxBackoff {
+		d = _sinkMaxBackoff
+	}
+	return d
+}
+
+func jitter(d time.Duration) time.Duration {
+	return d/2 + time.Duration(rand.Int63n(int64(d/2+1)))
+}
+
+// sinkRegistry hands out one bufferedSink per distinct SinkConfig so rules
+// that share a destination share a connection and a backoff state instead
+// of each dialing independently.
+type sinkRegistry struct {
+	mu    sync.Mutex
+	sinks map[string]*bufferedSink
+}
+
+var sinks = &sinkRegistry{sinks: make(map[string]*bufferedSink)}
+
+func (r *sinkRegistry) get(cfg SinkConfig) *bufferedSink {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.sinks[cfg.key()]; ok {
+		return s
+	}
+	s := newBufferedSink(cfg)
+	r.sinks[cfg.key()] = s
+	return s
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// stateStore persists each job's FileList baseline to disk, keyed by
+// AuditPlan.Key(), so a restart (crash, deploy, SIGHUP reload) doesn't
+// reset what "known" means and hide mutations that happened while the
+// daemon was down. It's a single JSON file rewritten in full on every
+// save rather than a real database: state.db is expected to hold at most
+// a few thousand FileData entries per rule, and a whole-file rewrite is
+// simpler than reasoning about a log-structured format for that size.
+type stateStore struct {
+	mu   sync.Mutex
+	path string
+	data map[string][]FileData
+}
+
+// openStateStore loads path if it exists and returns a store ready to use.
+// An empty path disables persistence: get always misses and save is a
+// no-op, so callers don't need to special-case "-state not set".
+func openStateStore(path string) *stateStore {
+	s := &stateStore{path: path, data: make(map[string][]FileData)}
+	if path == "" {
+		return s
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			Log(FacRules).Warnf("state: failed to read %s: %v", path, err)
+		}
+		return s
+	}
+
+	if err := json.Unmarshal(b, &s.data); err != nil {
+		Log(FacRules).Warnf("state: failed to parse %s, starting fresh: %v", path, err)
+		s.data = make(map[string][]FileData)
+	}
+
+	return s
+}
+
+// get returns the persisted baseline for key, if any.
+func (s *stateStore) get(key string) ([]FileData, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fd, ok := s.data[key]
+	return fd, ok
+}
+
+// save persists fd as the new baseline for key, replacing the whole file
+// via write-then-rename so a reader never sees a half written state.db.
+// A no-op when the store was opened without a path.
+func (s *stateStore) save(key string, fd []FileData) {
+	if s.path == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[key] = fd
+
+	b, err := json.Marshal(s.data)
+	if err != nil {
+		Log(FacRules).Errorf("state: marshal failed: %v", err)
+		return
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0640); err != nil {
+		Log(FacRules).Errorf("state: write %s failed: %v", tmp, err)
+		return
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		Log(FacRules).Errorf("state: rename %s to %s failed: %v", tmp, s.path, err)
+	}
+}
+
+// baseline is the process-wide state store, mirroring the sinks registry
+// pattern: a package-level var used directly by _buildJobs/AuditRun rather
+// than threaded through every function signature. main reassigns it from
+// -state once flags are parsed; until then (and always, when -state is
+// left blank) it behaves as a disabled store.
+var baseline = openStateStore("")
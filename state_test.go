@@ -0,0 +1,55 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateStoreDisabledByDefault(t *testing.T) {
+	s := openStateStore("")
+	s.save("d:/tmp", []FileData{{Name: "/tmp/f"}})
+
+	if _, ok := s.get("d:/tmp"); ok {
+		t.Error("get() found a baseline in a store opened without a path")
+	}
+}
+
+func TestStateStoreSaveGetRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	s := openStateStore(path)
+
+	want := []FileData{
+		{Name: "/tmp/a", Mtime: time.Unix(100, 0).UTC(), Size: 4, Hash: []byte{0xde, 0xad}},
+		{Name: "/tmp/b", Mtime: time.Unix(200, 0).UTC(), Size: 8},
+	}
+	s.save("f:/tmp", want)
+
+	got, ok := s.get("f:/tmp")
+	if !ok {
+		t.Fatal("get() missed a key just saved")
+	}
+	if len(got) != len(want) || got[0].Name != want[0].Name || got[1].Name != want[1].Name {
+		t.Errorf("get() = %+v, want %+v", got, want)
+	}
+}
+
+func TestStateStorePersistsAcrossOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+
+	s1 := openStateStore(path)
+	s1.save("f:/tmp", []FileData{{Name: "/tmp/a", Size: 1}})
+
+	s2 := openStateStore(path)
+	got, ok := s2.get("f:/tmp")
+	if !ok || len(got) != 1 || got[0].Name != "/tmp/a" {
+		t.Errorf("openStateStore(%s).get() = %+v, %v, want restored baseline", path, got, ok)
+	}
+}
+
+func TestStateStoreMissingFileStartsEmpty(t *testing.T) {
+	s := openStateStore(filepath.Join(t.TempDir(), "does-not-exist.db"))
+	if _, ok := s.get("f:/tmp"); ok {
+		t.Error("get() found a baseline for a store whose file didn't exist yet")
+	}
+}
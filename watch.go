@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const _watchDebounce = 500 * time.Millisecond
+
+// WatchJob is the event-driven counterpart to RunJob: instead of polling
+// job.Audit.Path on a ticker, it watches it via fsnotify and reacts to
+// Create/Write/Remove/Rename/Chmod as they happen. It emits the same Alerts
+// as AuditRun through the same sink, and falls back to RunJob's polling
+// loop wholesale if the watch can't be established (e.g. the platform's
+// watch-descriptor limit is exhausted).
+func WatchJob(ctx context.Context, job *AuditJob, jobNo, poll int, cfg SinkConfig) {
+	s := fmt.Sprintf("job[%d]:", jobNo)
+	jobLabel := strconv.Itoa(jobNo)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		Log(FacNotify).Warnf("%s fsnotify unavailable (%v), falling back to polling", s, err)
+		RunJob(ctx, job, jobNo, poll, cfg)
+		return
+	}
+	defer watcher.Close()
+
+	if err := _watchTree(watcher, job.Audit); err != nil {
+		Log(FacNotify).Warnf("%s watch-descriptor exhaustion establishing watches (%v), falling back to polling", s, err)
+		RunJob(ctx, job, jobNo, poll, cfg)
+		return
+	}
+
+	Log(FacNotify).Infof("%s entering notify loop on %s", s, job.Audit.Path)
+
+	if job.Restored {
+		Log(FacNotify).Infof("%s checking restored baseline for drift since last run", s)
+		AuditRun(job, jobNo, cfg)
+	}
+
+	db := &debouncer{pending: make(map[string]*time.Timer)}
+	defer db.stopAll()
+
+	for {
+		select {
+		case <-ctx.Done():
+			Log(FacNotify).Infof("%s shutting down", s)
+			return
+
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() && info.Name() != job.Audit.Prune {
+					if err := watcher.Add(ev.Name); err != nil {
+						Log(FacNotify).Errorf("%s unable to watch new directory %s: %v", s, ev.Name, err)
+					}
+				}
+			}
+
+			db.schedule(ev.Name, func() {
+				start := time.Now()
+				alert := _watchAlert(job, s, ev)
+
+				job.mu.Lock()
+				fileCount := len(*job.FileList)
+				job.mu.Unlock()
+
+				var alertBoard []Alert
+				if alert != nil {
+					Log(FacNotify).Infof("%s", alert.legacyLine())
+					alertBoard = []Alert{*alert}
+				}
+				recordRunMetrics(jobLabel, start, fileCount, alertBoard)
+
+				if len(alertBoard) > 0 {
+					dispatchAlerts(alertBoard, cfg)
+				}
+			})
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			Log(FacNotify).Errorf("%s fsnotify error: %v", s, err)
+		}
+	}
+}
+
+// _watchTree adds a recursive watch on every directory under ap.Path,
+// skipping ap.Prune, mirroring the pruning behaviour of _buildFileInvenory.
+func _watchTree(watcher *fsnotify.Watcher, ap AuditPlan) error {
+	return filepath.Walk(ap.Path, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if info.Name() == ap.Prune {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// _watchAlert turns a single fsnotify event into the same Alert shape
+// AuditRun emits, updating job.FileList so a later poll-mode comparison (or
+// a reload back to polling) stays consistent with what notify mode saw.
+// Returns nil when the event doesn't correspond to anything the rule audits.
+func _watchAlert(job *AuditJob, jobTag string, ev fsnotify.Event) *Alert {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	switch {
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		if job.Audit.Presence != 1 {
+			return nil
+		}
+		removed := false
+		kept := (*job.FileList)[:0]
+		for _, fd := range *job.FileList {
+			if fd.Name == ev.Name {
+				removed = true
+				continue
+			}
+			kept = append(kept, fd)
+		}
+		*job.FileList = kept
+		if !removed {
+			return nil
+		}
+		a := newAlert(jobTag, ev.Name, "file deleted", "", "")
+		return &a
+
+	case ev.Has(fsnotify.Create):
+		info, err := os.Stat(ev.Name)
+		if err != nil || info.IsDir() || job.Audit.Presence != 1 {
+			return nil
+		}
+		*job.FileList = append(*job.FileList, _newFileData(ev.Name, info, job.Audit))
+		a := newAlert(jobTag, ev.Name, "file added", "", "")
+		return &a
+
+	case ev.Has(fsnotify.Write), ev.Has(fsnotify.Chmod):
+		info, err := os.Stat(ev.Name)
+		if err != nil {
+			return nil
+		}
+		for i, fd := range *job.FileList {
+			if fd.Name != ev.Name {
+				continue
+			}
+			if ev.Has(fsnotify.Chmod) && job.Audit.Mode == 1 && info.Mode() != fd.Mode {
+				(*job.FileList)[i].Mode = info.Mode()
+				a := newAlert(jobTag, ev.Name, "mode change", fd.Mode.String(), info.Mode().String())
+				return &a
+			}
+			if ev.Has(fsnotify.Write) && job.Audit.Mtime == 1 && !info.ModTime().Equal(fd.Mtime) {
+				(*job.FileList)[i].Mtime = info.ModTime()
+				(*job.FileList)[i].Size = info.Size()
+				if job.Audit.Hash != "" && job.Audit.Hash != "none" {
+					// Refresh the stored hash too so it doesn't go stale and
+					// trigger a spurious "hash change" on the next pass; the
+					// mtime alert below already covers this edit.
+					(*job.FileList)[i].Hash = _hashFile(ev.Name, job.Audit.Hash)
+				}
+				a := newAlert(jobTag, ev.Name, "mtime change", fd.Mtime.String(), info.ModTime().String())
+				return &a
+			}
+			if ev.Has(fsnotify.Write) && job.Audit.Hash != "" && job.Audit.Hash != "none" {
+				h := _hashFile(ev.Name, job.Audit.Hash)
+				if !bytes.Equal(h, fd.Hash) {
+					(*job.FileList)[i].Hash = h
+					a := newAlert(jobTag, ev.Name, "hash change", "", job.Audit.Hash)
+					return &a
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// debouncer coalesces repeated events for the same path into a single
+// callback, so editors that write-then-rename don't produce an alert storm.
+type debouncer struct {
+	mu      sync.Mutex
+	pending map[string]*time.Timer
+}
+
+func (d *debouncer) schedule(path string, fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t, ok := d.pending[path]; ok {
+		t.Stop()
+	}
+	d.pending[path] = time.AfterFunc(_watchDebounce, func() {
+		d.mu.Lock()
+		delete(d.pending, path)
+		d.mu.Unlock()
+		fn()
+	})
+}
+
+func (d *debouncer) stopAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, t := range d.pending {
+		t.Stop()
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func newTestJob(ap AuditPlan, fd []FileData) *AuditJob {
+	list := append([]FileData(nil), fd...)
+	return &AuditJob{FileList: &list, Audit: ap}
+}
+
+func TestWatchAlertRemoveGatedOnPresence(t *testing.T) {
+	fd := []FileData{{Name: "/tmp/gone"}}
+
+	job := newTestJob(AuditPlan{Presence: 0}, fd)
+	if a := _watchAlert(job, "job[1]:", fsnotify.Event{Name: "/tmp/gone", Op: fsnotify.Remove}); a != nil {
+		t.Errorf("_watchAlert(Remove) with Presence=0 = %+v, want nil", a)
+	}
+	if len(*job.FileList) != 1 {
+		t.Errorf("FileList mutated despite Presence=0: %+v", *job.FileList)
+	}
+
+	job = newTestJob(AuditPlan{Presence: 1}, fd)
+	a := _watchAlert(job, "job[1]:", fsnotify.Event{Name: "/tmp/gone", Op: fsnotify.Remove})
+	if a == nil || a.Change != "file deleted" {
+		t.Fatalf("_watchAlert(Remove) with Presence=1 = %+v, want file deleted alert", a)
+	}
+	if len(*job.FileList) != 0 {
+		t.Errorf("FileList = %+v, want gone entry dropped", *job.FileList)
+	}
+}
+
+func TestWatchAlertCreate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "new")
+	if err := os.WriteFile(path, []byte("hi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job := newTestJob(AuditPlan{Presence: 1}, nil)
+	a := _watchAlert(job, "job[1]:", fsnotify.Event{Name: path, Op: fsnotify.Create})
+	if a == nil || a.Change != "file added" {
+		t.Fatalf("_watchAlert(Create) = %+v, want file added alert", a)
+	}
+	if len(*job.FileList) != 1 || (*job.FileList)[0].Name != path {
+		t.Errorf("FileList = %+v, want new entry tracked", *job.FileList)
+	}
+}
+
+func TestWatchAlertWriteMtime(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := newTestJob(AuditPlan{Mtime: 1}, []FileData{{Name: path, Mtime: info.ModTime().Add(-time.Second)}})
+	a := _watchAlert(job, "job[1]:", fsnotify.Event{Name: path, Op: fsnotify.Write})
+	if a == nil || a.Change != "mtime change" {
+		t.Fatalf("_watchAlert(Write) = %+v, want mtime change alert", a)
+	}
+}
+
+func TestWatchAlertWriteHash(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ap := AuditPlan{Mtime: 0, Hash: "sha256"}
+	job := newTestJob(ap, []FileData{{Name: path, Hash: _hashFile(path, "sha256")}})
+
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := _watchAlert(job, "job[1]:", fsnotify.Event{Name: path, Op: fsnotify.Write})
+	if a == nil || a.Change != "hash change" {
+		t.Fatalf("_watchAlert(Write) with Mtime disabled = %+v, want hash change alert", a)
+	}
+	if got := (*job.FileList)[0].Hash; string(got) == "" {
+		t.Error("FileList entry's Hash wasn't updated after a hash change alert")
+	}
+}
+
+func TestDebouncerCoalescesRapidEvents(t *testing.T) {
+	var calls int32
+	d := &debouncer{pending: make(map[string]*time.Timer)}
+	defer d.stopAll()
+
+	for i := 0; i < 5; i++ {
+		d.schedule("/tmp/f", func() { atomic.AddInt32(&calls, 1) })
+	}
+
+	time.Sleep(_watchDebounce + 250*time.Millisecond)
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("debouncer fired %d times for 5 rapid schedules, want 1", got)
+	}
+}